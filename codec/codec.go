@@ -0,0 +1,45 @@
+// Package codec 定义RPC请求/响应的编解码抽象，以及按MIME风格类型名索引的
+// Codec构造函数注册表，Server据此按连接协商出的编码类型选择具体实现。
+package codec
+
+import "io"
+
+// Header 是每次RPC调用的元信息，独立于body之外传输。
+type Header struct {
+	ServiceMethod string //形如 Service.Method
+	Seq           uint64 //请求序号，用于调用方匹配异步响应
+	Error         string //服务端处理失败时回写的错误信息，为空表示调用成功
+}
+
+// Codec 定义了一种具体编码协议下读写Header/Body的能力，每个连接对应一个Codec实例。
+type Codec interface {
+	ReadHeader(*Header) error
+	ReadBody(interface{}) error
+	Write(*Header, interface{}) error
+	io.Closer
+}
+
+// NewCodecFunc 根据一个已建立的连接构造出对应编码协议的Codec。
+type NewCodecFunc func(io.ReadWriteCloser) Codec
+
+// MIME风格的编码类型名，Register/Get以及Server.RegisterCodec都按该命名风格登记。
+const (
+	TypeGob  = "application/gob"
+	TypeJSON = "application/json"
+)
+
+var codecs = map[string]NewCodecFunc{
+	TypeGob:  NewGobCodec,
+	TypeJSON: NewJSONCodec,
+}
+
+// Register 登记一个编码类型的构造函数，已存在的同名类型会被覆盖。
+func Register(typ string, f NewCodecFunc) {
+	codecs[typ] = f
+}
+
+// Get 按类型名取出构造函数，不存在时ok为false。
+func Get(typ string) (f NewCodecFunc, ok bool) {
+	f, ok = codecs[typ]
+	return f, ok
+}
@@ -0,0 +1,114 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/gob"
+	"reflect"
+	"testing"
+)
+
+// pipeConn 用一个bytes.Buffer模拟io.ReadWriteCloser，Write和Read共享同一段
+// 底层缓冲区，足够覆盖一个Codec实例先Write、另一个Codec实例再从同一缓冲区Read的往返场景。
+type pipeConn struct {
+	*bytes.Buffer
+}
+
+func (pipeConn) Close() error { return nil }
+
+func newPipeConn() pipeConn {
+	return pipeConn{Buffer: new(bytes.Buffer)}
+}
+
+type roundTripBody struct {
+	Name string
+	Tags map[string]string
+}
+
+// animal是一个带接口字段场景下用到的具体类型，Gob要求提前Register才能解码到
+// interface{}字段里。
+type animal struct {
+	Kind string
+}
+
+// zooBody 带一个interface类型的字段，运行时装的是animal。
+type zooBody struct {
+	Pet interface{}
+}
+
+func roundTrip(t *testing.T, typ string, in *Header, inBody interface{}, outBody interface{}) {
+	t.Helper()
+
+	f, ok := Get(typ)
+	if !ok {
+		t.Fatalf("codec type %q not registered", typ)
+	}
+
+	conn := newPipeConn()
+	writer := f(conn)
+	if err := writer.Write(in, inBody); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	reader := f(conn)
+	var outHeader Header
+	if err := reader.ReadHeader(&outHeader); err != nil {
+		t.Fatalf("ReadHeader: %v", err)
+	}
+	if outHeader != *in {
+		t.Fatalf("header mismatch: got %+v, want %+v", outHeader, *in)
+	}
+
+	if err := reader.ReadBody(outBody); err != nil {
+		t.Fatalf("ReadBody: %v", err)
+	}
+}
+
+func TestGobCodecRoundTripStruct(t *testing.T) {
+	in := &Header{ServiceMethod: "Foo.Bar", Seq: 1}
+	body := roundTripBody{Name: "svc", Tags: map[string]string{"zone": "a", "env": "prod"}}
+
+	var out roundTripBody
+	roundTrip(t, TypeGob, in, &body, &out)
+
+	if !reflect.DeepEqual(body, out) {
+		t.Fatalf("body mismatch: got %+v, want %+v", out, body)
+	}
+}
+
+func TestGobCodecRoundTripInterfaceField(t *testing.T) {
+	gob.Register(animal{})
+
+	in := &Header{ServiceMethod: "Zoo.Describe", Seq: 2}
+	inBody := zooBody{Pet: animal{Kind: "cat"}}
+
+	var out zooBody
+	roundTrip(t, TypeGob, in, &inBody, &out)
+
+	got, ok := out.Pet.(animal)
+	if !ok {
+		t.Fatalf("expected out.Pet to decode as animal, got %T", out.Pet)
+	}
+	if got != inBody.Pet.(animal) {
+		t.Fatalf("interface field mismatch: got %+v, want %+v", got, inBody.Pet)
+	}
+}
+
+func TestJSONCodecRoundTripMap(t *testing.T) {
+	in := &Header{ServiceMethod: "Foo.Bar", Seq: 3}
+	body := map[string]interface{}{"count": float64(2), "name": "svc"}
+
+	var out map[string]interface{}
+	roundTrip(t, TypeJSON, in, body, &out)
+
+	if !reflect.DeepEqual(body, out) {
+		t.Fatalf("body mismatch: got %+v, want %+v", out, body)
+	}
+}
+
+func TestJSONCodecRoundTripError(t *testing.T) {
+	in := &Header{ServiceMethod: "Foo.Bar", Seq: 4, Error: "boom"}
+	body := roundTripBody{Name: "svc"}
+
+	var out roundTripBody
+	roundTrip(t, TypeJSON, in, &body, &out)
+}
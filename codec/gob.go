@@ -0,0 +1,60 @@
+package codec
+
+import (
+	"bufio"
+	"encoding/gob"
+	"io"
+)
+
+// GobCodec 是基于encoding/gob的Codec实现，要求双方交互用到的具体类型提前
+// 通过gob.Register登记，否则interface类型的字段无法正确解码。
+type GobCodec struct {
+	conn io.ReadWriteCloser
+	buf  *bufio.Writer
+	dec  *gob.Decoder
+	enc  *gob.Encoder
+}
+
+// NewGobCodec 基于conn构造一个GobCodec，写入先经过buf缓冲，Write时统一Flush。
+func NewGobCodec(conn io.ReadWriteCloser) Codec {
+	buf := bufio.NewWriter(conn)
+	return &GobCodec{
+		conn: conn,
+		buf:  buf,
+		dec:  gob.NewDecoder(conn),
+		enc:  gob.NewEncoder(buf),
+	}
+}
+
+// ReadHeader 实现 Codec.ReadHeader。
+func (c *GobCodec) ReadHeader(h *Header) error {
+	return c.dec.Decode(h)
+}
+
+// ReadBody 实现 Codec.ReadBody。
+func (c *GobCodec) ReadBody(body interface{}) error {
+	return c.dec.Decode(body)
+}
+
+// Write 实现 Codec.Write，依次编码Header和body，最后统一Flush，
+// 任意一步失败都会关闭连接，避免半包脏数据被后续调用读到。
+func (c *GobCodec) Write(h *Header, body interface{}) (err error) {
+	defer func() {
+		if ferr := c.buf.Flush(); err == nil {
+			err = ferr
+		}
+		if err != nil {
+			_ = c.Close()
+		}
+	}()
+
+	if err = c.enc.Encode(h); err != nil {
+		return err
+	}
+	return c.enc.Encode(body)
+}
+
+// Close 实现 Codec.Close，关闭底层连接。
+func (c *GobCodec) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,52 @@
+package codec
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// JSONCodec 是基于encoding/json的Codec实现，用流式Encoder/Decoder收发，
+// 跨语言场景下比Gob更通用，但不能像Gob那样透明处理interface类型字段，
+// body的具体类型需要由调用方在ReadBody前准备好。
+type JSONCodec struct {
+	conn io.ReadWriteCloser
+	dec  *json.Decoder
+	enc  *json.Encoder
+}
+
+// NewJSONCodec 基于conn构造一个JSONCodec。
+func NewJSONCodec(conn io.ReadWriteCloser) Codec {
+	return &JSONCodec{
+		conn: conn,
+		dec:  json.NewDecoder(conn),
+		enc:  json.NewEncoder(conn),
+	}
+}
+
+// ReadHeader 实现 Codec.ReadHeader，Header.Error字段按普通字符串字段解码。
+func (c *JSONCodec) ReadHeader(h *Header) error {
+	return c.dec.Decode(h)
+}
+
+// ReadBody 实现 Codec.ReadBody。
+func (c *JSONCodec) ReadBody(body interface{}) error {
+	return c.dec.Decode(body)
+}
+
+// Write 实现 Codec.Write，依次编码Header和body，任意一步失败都关闭连接。
+func (c *JSONCodec) Write(h *Header, body interface{}) error {
+	if err := c.enc.Encode(h); err != nil {
+		_ = c.Close()
+		return err
+	}
+	if err := c.enc.Encode(body); err != nil {
+		_ = c.Close()
+		return err
+	}
+	return nil
+}
+
+// Close 实现 Codec.Close，关闭底层连接。
+func (c *JSONCodec) Close() error {
+	return c.conn.Close()
+}
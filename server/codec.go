@@ -0,0 +1,27 @@
+package server
+
+import "github.com/Is-Raining/myTinyRpc/codec"
+
+// RegisterCodec 为Server登记一个编码类型的构造函数，Service可以按连接建立时
+// 客户端option字节协商出的ServiceDesc.CodecType，通过Server.Codec取出对应实现。
+// 已登记的类型名会覆盖codec包的默认实现。
+func (s *Server) RegisterCodec(name string, f codec.NewCodecFunc) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.codecs == nil {
+		s.codecs = make(map[string]codec.NewCodecFunc)
+	}
+	s.codecs[name] = f
+}
+
+// Codec 按类型名取出编码构造函数，优先取Server自己登记过的实现，
+// 否则回退到codec包的全局默认注册表（application/gob、application/json）。
+func (s *Server) Codec(name string) (codec.NewCodecFunc, bool) {
+	s.mux.Lock()
+	f, ok := s.codecs[name]
+	s.mux.Unlock()
+	if ok {
+		return f, true
+	}
+	return codec.Get(name)
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// drainPollInterval 是等待InFlight归零时的轮询间隔。
+const drainPollInterval = 50 * time.Millisecond
+
+// InFlight 返回当前所有Service正在处理中的请求数，供优雅关闭判断排水是否完成，
+// 也可以直接暴露给监控系统。
+func (s *Server) InFlight() int {
+	return int(atomic.LoadInt64(&s.inFlight))
+}
+
+// AddInFlight 将in-flight请求计数加1，Service的请求入口应在开始处理请求时调用。
+func (s *Server) AddInFlight() {
+	atomic.AddInt64(&s.inFlight, 1)
+}
+
+// DoneInFlight 将in-flight请求计数减1，Service应在请求处理完成（无论成功失败）时调用。
+func (s *Server) DoneInFlight() {
+	atomic.AddInt64(&s.inFlight, -1)
+}
+
+// drain 对每个未失败、实现了Draining接口的Service调用Drain，使其拒绝新请求，
+// 然后等待RejectAfter宽限期过去后，轮询InFlight直到归零或ctx超时为止。
+func (s *Server) drain(ctx context.Context) {
+	for name, service := range s.services {
+		if _, ok := s.failedServices.Load(name); ok {
+			continue
+		}
+		d, ok := service.(Draining)
+		if !ok {
+			continue
+		}
+		if err := d.Drain(); err != nil {
+			//排水失败，不影响后续的强制关闭
+		}
+	}
+
+	if s.RejectAfter > 0 {
+		select {
+		case <-time.After(s.RejectAfter):
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for {
+		if s.InFlight() <= 0 {
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
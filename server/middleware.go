@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime"
+	"time"
+)
+
+// Request 是RPC请求的占位类型，具体报文结构由各Service自行解析，
+// Handle只负责把它透传给中间件链。
+type Request interface{}
+
+// HandlerFunc 是单次RPC调用最终处理函数的统一形态，所有Service都通过
+// Server.Handle触发同一套中间件链后进入这里。
+type HandlerFunc func(ctx context.Context, req Request, rsp interface{}) error
+
+// HandlerWrapper 包装一个HandlerFunc并返回新的HandlerFunc，多个Wrapper按
+// 注册顺序像洋葱一样嵌套：先注册的在外层，最先执行。
+type HandlerWrapper func(HandlerFunc) HandlerFunc
+
+// Use 追加一个或多个HandlerWrapper到Server的调用链，按传入顺序依次包裹，
+// 链上的每个Wrapper对所有Service的请求统一生效。
+func (s *Server) Use(wrappers ...HandlerWrapper) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.wrappers = append(s.wrappers, wrappers...)
+}
+
+// Handle 是Service统一的调用入口：先经过开启的过载保护判断是否丢弃本次调用，
+// 再把final包裹进已注册的中间件链后执行，让所有transport共享同一套in-flight
+// 统计、panic恢复、超时控制等横切逻辑。
+func (s *Server) Handle(ctx context.Context, req Request, rsp interface{}, final HandlerFunc) error {
+	if s.overload != nil {
+		if !s.overload.Allow() {
+			return ErrOverload
+		}
+		finish := s.overload.Begin()
+		defer finish()
+	}
+
+	s.mux.Lock()
+	wrappers := s.wrappers
+	s.mux.Unlock()
+
+	h := final
+	for i := len(wrappers) - 1; i >= 0; i-- {
+		h = wrappers[i](h)
+	}
+	return h(ctx, req, rsp)
+}
+
+// InFlightWrapper 返回一个记录in-flight请求数的HandlerWrapper，语义上等价于
+// sync.WaitGroup的Add/Done，底层复用Server.AddInFlight/DoneInFlight这对无锁计数器，
+// 与Drain模式配合，让tryClose能判断存量请求是否已经跑完。
+func InFlightWrapper(s *Server) HandlerWrapper {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, req Request, rsp interface{}) error {
+			s.AddInFlight()
+			defer s.DoneInFlight()
+			return next(ctx, req, rsp)
+		}
+	}
+}
+
+// RecoveryWrapper 返回一个捕获panic的HandlerWrapper，区分runtime.Error（数组越界、
+// 空指针等运行时错误）与普通panic分别打日志，并把panic转换为error返回，避免单次
+// 调用的panic打垮整个进程。
+func RecoveryWrapper() HandlerWrapper {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, req Request, rsp interface{}) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					if rerr, ok := r.(runtime.Error); ok {
+						//运行时错误，比如数组越界、空指针
+						log.Printf("handler panic (runtime error): %v", rerr)
+						err = fmt.Errorf("panic recovered (runtime error): %w", rerr)
+					} else {
+						log.Printf("handler panic: %v", r)
+						err = fmt.Errorf("panic recovered: %v", r)
+					}
+				}
+			}()
+			return next(ctx, req, rsp)
+		}
+	}
+}
+
+// TimeoutWrapper 返回一个按defaultTimeout派生per-call context.WithTimeout的
+// HandlerWrapper，defaultTimeout<=0时不做任何处理，沿用调用方传入的ctx。
+func TimeoutWrapper(defaultTimeout time.Duration) HandlerWrapper {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, req Request, rsp interface{}) error {
+			if defaultTimeout <= 0 {
+				return next(ctx, req, rsp)
+			}
+			ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+			defer cancel()
+			return next(ctx, req, rsp)
+		}
+	}
+}
+
+// TracingHook 是预留给链路追踪/监控埋点的HandlerWrapper扩展点，默认只透传调用，
+// hook为nil时等价于空操作，具体实现可以替换hook接入自己的tracing/metrics系统。
+func TracingHook(hook func(ctx context.Context, req Request)) HandlerWrapper {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, req Request, rsp interface{}) error {
+			if hook != nil {
+				hook(ctx, req)
+			}
+			return next(ctx, req, rsp)
+		}
+	}
+}
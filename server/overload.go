@@ -0,0 +1,295 @@
+package server
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrOverload 在自适应过载保护判定需要丢弃本次请求时返回，调用方应将其视为
+// 可重试的错误（等价于HTTP的503）。
+var ErrOverload = errors.New("server overloaded, please retry later")
+
+const (
+	defaultBucketDuration = 500 * time.Millisecond
+	defaultBucketCount    = 10
+	defaultCPUThreshold   = 0.8
+	defaultCooldown       = 5 * time.Second
+)
+
+// bucket 是滑动窗口里的一个时间片，统计落在该时间片内完成的请求数，以及
+// 该时间片内观测到的最小时延。
+type bucket struct {
+	count int64
+	minRT time.Duration //0表示该bucket内还没有样本
+}
+
+// cpuSample 是一次/proc/stat CPU time快照，配合上一次快照算出区间内的使用率。
+type cpuSample struct {
+	idle, total uint64
+}
+
+// OverloadProtector 是一个BBR风格的自适应准入控制器：用滑动窗口估算系统当前
+// 能扛住的最大吞吐maxPass和最小时延minRT，算出expected = maxPass * minRT作为
+// 系统“合理的在途请求数”。一旦InFlight超过expected，并且CPU使用率超过阈值或者
+// 上一次丢弃触发的冷却时间还没过去，就拒绝新请求，直到系统恢复。
+type OverloadProtector struct {
+	bucketDuration time.Duration
+	bucketCount    int
+	cpuThreshold   float64
+	cooldown       time.Duration
+
+	mu            sync.Mutex
+	buckets       []bucket
+	cursor        int
+	lastTick      time.Time
+	coolDownEnd   time.Time
+	lastCPUSample cpuSample
+
+	inFlight int64
+}
+
+// OverloadProtectorOption 用于定制OverloadProtector的阈值、窗口大小等参数。
+type OverloadProtectorOption func(*OverloadProtector)
+
+// WithCPUThreshold 设置触发丢弃判断所需的CPU使用率阈值，取值范围(0, 1]，默认0.8。
+func WithCPUThreshold(pct float64) OverloadProtectorOption {
+	return func(p *OverloadProtector) { p.cpuThreshold = pct }
+}
+
+// WithWindowBuckets 设置滑动窗口的bucket数量，默认10。
+func WithWindowBuckets(n int) OverloadProtectorOption {
+	return func(p *OverloadProtector) {
+		if n > 0 {
+			p.bucketCount = n
+		}
+	}
+}
+
+// WithBucketDuration 设置单个bucket覆盖的时间长度，默认500ms。
+func WithBucketDuration(d time.Duration) OverloadProtectorOption {
+	return func(p *OverloadProtector) {
+		if d > 0 {
+			p.bucketDuration = d
+		}
+	}
+}
+
+// WithCooldown 设置一次丢弃触发后的冷却时长，冷却期内即使CPU回落也继续按
+// 过载处理，避免在临界点上反复抖动，默认5s。
+func WithCooldown(d time.Duration) OverloadProtectorOption {
+	return func(p *OverloadProtector) {
+		if d > 0 {
+			p.cooldown = d
+		}
+	}
+}
+
+// newOverloadProtector 按默认值加opts构造一个OverloadProtector。
+func newOverloadProtector(opts ...OverloadProtectorOption) *OverloadProtector {
+	p := &OverloadProtector{
+		bucketDuration: defaultBucketDuration,
+		bucketCount:    defaultBucketCount,
+		cpuThreshold:   defaultCPUThreshold,
+		cooldown:       defaultCooldown,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	p.buckets = make([]bucket, p.bucketCount)
+	return p
+}
+
+// WithOverloadProtector 为Server开启自适应过载保护，返回Server自身以便链式调用。
+func (s *Server) WithOverloadProtector(opts ...OverloadProtectorOption) *Server {
+	s.overload = newOverloadProtector(opts...)
+	return s
+}
+
+// advance 根据当前时间推进游标，跨越的bucket清零复用，持有mu时调用。
+func (p *OverloadProtector) advance(now time.Time) {
+	if p.lastTick.IsZero() {
+		p.lastTick = now
+		return
+	}
+	steps := int(now.Sub(p.lastTick) / p.bucketDuration)
+	if steps <= 0 {
+		return
+	}
+	if steps > p.bucketCount {
+		steps = p.bucketCount
+	}
+	for i := 0; i < steps; i++ {
+		p.cursor = (p.cursor + 1) % p.bucketCount
+		p.buckets[p.cursor] = bucket{}
+	}
+	p.lastTick = now
+}
+
+// maxPassLocked 取窗口内各bucket吞吐(count/bucketDuration)的最大值，持有mu时调用。
+func (p *OverloadProtector) maxPassLocked() float64 {
+	var max float64
+	for _, b := range p.buckets {
+		pass := float64(b.count) / p.bucketDuration.Seconds()
+		if pass > max {
+			max = pass
+		}
+	}
+	return max
+}
+
+// minRTLocked 取窗口内各bucket最小时延里的最小值，作为时延的下限，
+// 窗口内还没有样本时退化为bucketDuration，持有mu时调用。
+func (p *OverloadProtector) minRTLocked() time.Duration {
+	var min time.Duration
+	for _, b := range p.buckets {
+		if b.minRT <= 0 {
+			continue
+		}
+		if min == 0 || b.minRT < min {
+			min = b.minRT
+		}
+	}
+	if min == 0 {
+		return p.bucketDuration
+	}
+	return min
+}
+
+// MaxPass 返回窗口内观测到的最大成功吞吐（次/秒）。
+func (p *OverloadProtector) MaxPass() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.maxPassLocked()
+}
+
+// MinRT 返回窗口内观测到的最小请求时延。
+func (p *OverloadProtector) MinRT() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.minRTLocked()
+}
+
+// InFlight 返回当前经过该保护器的在途请求数。
+func (p *OverloadProtector) InFlight() int64 {
+	return atomic.LoadInt64(&p.inFlight)
+}
+
+// Allow 在dispatch前调用，返回false表示本次调用应该以ErrOverload拒绝。
+func (p *OverloadProtector) Allow() bool {
+	now := time.Now()
+
+	p.mu.Lock()
+	p.advance(now)
+	expected := p.maxPassLocked() * p.minRTLocked().Seconds()
+	cooling := now.Before(p.coolDownEnd)
+	p.mu.Unlock()
+
+	inFlight := float64(p.InFlight())
+	if inFlight <= expected {
+		return true
+	}
+	if !cooling && p.cpuUsage() <= p.cpuThreshold {
+		return true
+	}
+
+	p.mu.Lock()
+	p.coolDownEnd = now.Add(p.cooldown)
+	p.mu.Unlock()
+	return false
+}
+
+// Begin 在进入业务处理前调用，返回一个finish函数，finish应在请求处理完成
+// （无论成功失败）后调用，记录本次请求的时延样本并维护in-flight计数。
+func (p *OverloadProtector) Begin() func() {
+	atomic.AddInt64(&p.inFlight, 1)
+	start := time.Now()
+	return func() {
+		atomic.AddInt64(&p.inFlight, -1)
+		rt := time.Since(start)
+
+		p.mu.Lock()
+		p.advance(time.Now())
+		b := &p.buckets[p.cursor]
+		b.count++
+		if b.minRT == 0 || rt < b.minRT {
+			b.minRT = rt
+		}
+		p.mu.Unlock()
+	}
+}
+
+// cpuUsage 优先从/proc/stat采样CPU使用率（Linux），非Linux或读取失败时回退到
+// 用runtime计数器粗略估算。
+func (p *OverloadProtector) cpuUsage() float64 {
+	sample, ok := readProcStatCPU()
+	if !ok {
+		return runtimeCPUUsage()
+	}
+
+	p.mu.Lock()
+	prev := p.lastCPUSample
+	p.lastCPUSample = sample
+	p.mu.Unlock()
+
+	if prev.total == 0 || sample.total <= prev.total {
+		return 0
+	}
+	totalDelta := sample.total - prev.total
+	idleDelta := sample.idle - prev.idle
+	if idleDelta > totalDelta {
+		return 0
+	}
+	return 1 - float64(idleDelta)/float64(totalDelta)
+}
+
+// readProcStatCPU 读取/proc/stat的聚合cpu行，算出idle与total的累计tick数。
+func readProcStatCPU() (cpuSample, bool) {
+	f, err := os.Open("/proc/stat")
+	if err != nil {
+		return cpuSample{}, false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() {
+		return cpuSample{}, false
+	}
+	fields := strings.Fields(scanner.Text())
+	if len(fields) < 5 || fields[0] != "cpu" {
+		return cpuSample{}, false
+	}
+
+	var sample cpuSample
+	for i, field := range fields[1:] {
+		v, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			continue
+		}
+		sample.total += v
+		if i == 3 { // fields[1:][3] 对应/proc/stat的idle列
+			sample.idle = v
+		}
+	}
+	return sample, true
+}
+
+// runtimeCPUUsage 是/proc/stat不可用时的兜底估算，用goroutine数相对GOMAXPROCS
+// 的比值粗略代替真实CPU使用率，精度不高但能避免非Linux平台完全失去保护能力。
+func runtimeCPUUsage() float64 {
+	procs := runtime.GOMAXPROCS(0)
+	if procs <= 0 {
+		procs = 1
+	}
+	usage := float64(runtime.NumGoroutine()) / float64(procs*256)
+	if usage > 1 {
+		usage = 1
+	}
+	return usage
+}
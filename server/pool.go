@@ -0,0 +1,138 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+)
+
+// ErrPoolClosed 在向一个已经Close的workerPool提交任务时返回。
+var ErrPoolClosed = errors.New("worker pool closed")
+
+// workerPool 是一个有界的goroutine池：固定数量的worker从一个有界队列里取任务
+// 执行，用来替代"一个请求/一次关闭起一个goroutine"的裸自增长模型，避免高并发
+// 下goroutine数量失控。
+type workerPool struct {
+	mu      sync.Mutex
+	tasks   chan func()
+	stopChs []chan struct{}
+
+	closeCh   chan struct{}
+	closeOnce sync.Once
+}
+
+// newWorkerPool 创建一个size个worker、队列深度为queueDepth的workerPool并立即启动。
+func newWorkerPool(size, queueDepth int) *workerPool {
+	if queueDepth < 0 {
+		queueDepth = 0
+	}
+	p := &workerPool{
+		tasks:   make(chan func(), queueDepth),
+		closeCh: make(chan struct{}),
+	}
+	p.Resize(size)
+	return p
+}
+
+// Resize 动态调整worker数量：增加时拉起新worker，减少时停掉多余的worker
+// （当前任务执行完成后退出），size<=0时按1处理。
+func (p *workerPool) Resize(size int) {
+	if size <= 0 {
+		size = 1
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	cur := len(p.stopChs)
+	switch {
+	case size > cur:
+		for i := 0; i < size-cur; i++ {
+			stop := make(chan struct{})
+			p.stopChs = append(p.stopChs, stop)
+			p.spawn(stop)
+		}
+	case size < cur:
+		for i := cur - 1; i >= size; i-- {
+			close(p.stopChs[i])
+			p.stopChs = p.stopChs[:i]
+		}
+	}
+}
+
+// spawn 启动一个worker，worker持续从tasks里取任务执行，直到收到stop或pool被Close。
+func (p *workerPool) spawn(stop chan struct{}) {
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-p.closeCh:
+				return
+			case task := <-p.tasks:
+				runTask(task)
+			}
+		}
+	}()
+}
+
+// runTask 执行一个任务并恢复panic，会区分runtime.Error与普通panic，但两者都只是
+// 被吞掉，不记录日志也不返回给调用方，确保单个任务的panic不会打垮worker所在的goroutine。
+func runTask(task func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(runtime.Error); ok {
+				//运行时panic，比如数组越界、空指针，任务本身已经无法恢复
+			} else {
+				//普通panic，同样吞掉避免worker退出
+			}
+		}
+	}()
+	task()
+}
+
+// Submit 把fn提交到任务队列，ctx被取消或队列一直满时返回ctx.Err()，
+// pool已经Close时返回ErrPoolClosed。
+func (p *workerPool) Submit(ctx context.Context, fn func()) error {
+	select {
+	case <-p.closeCh:
+		return ErrPoolClosed
+	default:
+	}
+
+	select {
+	case p.tasks <- fn:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-p.closeCh:
+		return ErrPoolClosed
+	}
+}
+
+// Close 停止所有worker，之后的Submit都会返回ErrPoolClosed。
+func (p *workerPool) Close() {
+	p.closeOnce.Do(func() {
+		close(p.closeCh)
+	})
+}
+
+// WithWorkerPool 为Server开启有界协程池，tryClose的服务关闭任务以及通过
+// Server.Go调度的连接处理函数都会提交到该池里排队执行，返回Server自身以便
+// 链式调用。
+func (s *Server) WithWorkerPool(size, queueDepth int) *Server {
+	s.pool = newWorkerPool(size, queueDepth)
+	return s
+}
+
+// Go 调度一个函数执行：如果Server通过WithWorkerPool开启了有界协程池，任务
+// 提交到池里排队执行；否则退化为裸起一个goroutine，保持未开启该功能时的
+// 原有行为。
+func (s *Server) Go(ctx context.Context, fn func()) error {
+	if s.pool == nil {
+		go fn()
+		return nil
+	}
+	return s.pool.Submit(ctx, fn)
+}
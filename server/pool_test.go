@@ -0,0 +1,49 @@
+package server
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+// benchConcurrency 对应request里要求验证的“100k并发请求”场景。
+const benchConcurrency = 100000
+
+// BenchmarkWorkerPoolDispatch 用固定大小的workerPool调度100k个并发任务，
+// 用来和BenchmarkNaiveSpawnPerRequest对比：协程池把稳态goroutine/内存占用
+// 维持在worker数量级别，而不是随并发请求数线性增长。
+func BenchmarkWorkerPoolDispatch(b *testing.B) {
+	pool := newWorkerPool(runtime.GOMAXPROCS(0), benchConcurrency)
+	defer pool.Close()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var wg sync.WaitGroup
+		wg.Add(benchConcurrency)
+		for j := 0; j < benchConcurrency; j++ {
+			_ = pool.Submit(context.Background(), func() {
+				defer wg.Done()
+			})
+		}
+		wg.Wait()
+	}
+}
+
+// BenchmarkNaiveSpawnPerRequest 是“每个请求裸起一个goroutine”的基线对照，
+// 不经过workerPool，直接go func()处理每个任务。
+func BenchmarkNaiveSpawnPerRequest(b *testing.B) {
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var wg sync.WaitGroup
+		wg.Add(benchConcurrency)
+		for j := 0; j < benchConcurrency; j++ {
+			go func() {
+				defer wg.Done()
+			}()
+		}
+		wg.Wait()
+	}
+}
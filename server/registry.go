@@ -0,0 +1,188 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// defaultHeartbeatDivisor 用于在未显式设置HeartbeatInterval时，按Instance.TTL的
+// 多少分之一计算心跳周期，TTL/3是名字服务场景下的常见经验值。
+const defaultHeartbeatDivisor = 3
+
+// Instance 描述一个注册到名字服务的服务实例。
+type Instance struct {
+	ServiceName string            //服务名
+	Addr        string            //服务监听地址，如 ip:port
+	Metadata    map[string]string //附加元数据，比如版本号、机房等
+	TTL         time.Duration     //实例在名字服务上的存活时间，超过TTL未续约则视为下线
+}
+
+// Registry 是名字服务的注册发现接口，Nacos/etcd/Consul等具体实现都应满足该接口，
+// 通过Server.WithRegistry接入。
+type Registry interface {
+	// Register 将instance注册到名字服务，返回的cancel用于主动注销这次注册。
+	Register(ctx context.Context, instance *Instance) (cancel func(), err error)
+	// Deregister 将instance从名字服务上删除。
+	Deregister(ctx context.Context, instance *Instance) error
+	// KeepAlive 向名字服务发送一次心跳，刷新instance的TTL。
+	KeepAlive(ctx context.Context, instance *Instance) error
+}
+
+// InstanceProvider 是一个可选接口，Service实现后可以提供自己的名字服务实例信息，
+// Server在启动阶段据此调用Registry完成注册与心跳续约。
+type InstanceProvider interface {
+	Instance() *Instance
+}
+
+// WithRegistry 为Server配置名字服务注册中心，返回Server自身以便链式调用。
+func (s *Server) WithRegistry(r Registry) *Server {
+	s.registry = r
+	return s
+}
+
+// registerAll 遍历所有实现了InstanceProvider的Service，向registry完成注册并为
+// 每个实例启动一个按HeartbeatInterval（默认TTL/3）续约的心跳goroutine。
+func (s *Server) registerAll(ctx context.Context) {
+	if s.registry == nil {
+		return
+	}
+
+	s.mux.Lock()
+	if s.registryCancels == nil {
+		s.registryCancels = make(map[string]func())
+	}
+	s.mux.Unlock()
+
+	for name, service := range s.services {
+		provider, ok := service.(InstanceProvider)
+		if !ok {
+			continue
+		}
+		instance := provider.Instance()
+		if instance == nil {
+			continue
+		}
+
+		cancel, err := s.registry.Register(ctx, instance)
+		if err != nil {
+			s.failedServices.Store(name, err)
+			continue
+		}
+
+		s.mux.Lock()
+		s.registryCancels[name] = cancel
+		s.mux.Unlock()
+
+		go s.heartbeat(name, instance)
+	}
+}
+
+// heartbeat 按HeartbeatInterval（未设置时取instance.TTL/defaultHeartbeatDivisor）
+// 周期性地为instance续约，直到Server关闭或某一次续约失败为止。
+func (s *Server) heartbeat(name string, instance *Instance) {
+	interval := s.HeartbeatInterval
+	if interval <= 0 {
+		interval = instance.TTL / defaultHeartbeatDivisor
+	}
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.registry.KeepAlive(context.Background(), instance); err != nil {
+				// 心跳失败，登记为失败服务，tryClose会跳过该服务的关闭与注销
+				s.failedServices.Store(name, err)
+				return
+			}
+		case <-s.closeCh:
+			return
+		}
+	}
+}
+
+// deregisterAll 在tryClose排水之前调用，把未失败的服务从名字服务上摘除，
+// 让负载均衡尽快停止往本实例路由流量。
+func (s *Server) deregisterAll(ctx context.Context) {
+	if s.registry == nil {
+		return
+	}
+
+	for name, service := range s.services {
+		if _, failed := s.failedServices.Load(name); failed {
+			continue
+		}
+		provider, ok := service.(InstanceProvider)
+		if !ok {
+			continue
+		}
+		instance := provider.Instance()
+		if instance == nil {
+			continue
+		}
+		if err := s.registry.Deregister(ctx, instance); err != nil {
+			//注销失败，名字服务侧会在TTL过期后自动摘除
+		}
+
+		s.mux.Lock()
+		if cancel, ok := s.registryCancels[name]; ok {
+			cancel()
+			delete(s.registryCancels, name)
+		}
+		s.mux.Unlock()
+	}
+}
+
+// MemoryRegistry 是一个进程内的Registry实现，不依赖任何外部组件，用于测试以及
+// 单机场景下的占位实现。
+type MemoryRegistry struct {
+	mu        sync.Mutex
+	instances map[string]*Instance
+}
+
+// NewMemoryRegistry 创建一个空的MemoryRegistry。
+func NewMemoryRegistry() *MemoryRegistry {
+	return &MemoryRegistry{instances: make(map[string]*Instance)}
+}
+
+func memoryRegistryKey(instance *Instance) string {
+	return instance.ServiceName + "@" + instance.Addr
+}
+
+// Register 实现 Registry.Register。
+func (r *MemoryRegistry) Register(_ context.Context, instance *Instance) (func(), error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := memoryRegistryKey(instance)
+	r.instances[key] = instance
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.instances, key)
+	}, nil
+}
+
+// Deregister 实现 Registry.Deregister。
+func (r *MemoryRegistry) Deregister(_ context.Context, instance *Instance) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.instances, memoryRegistryKey(instance))
+	return nil
+}
+
+// KeepAlive 实现 Registry.KeepAlive。
+func (r *MemoryRegistry) KeepAlive(_ context.Context, instance *Instance) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := memoryRegistryKey(instance)
+	if _, ok := r.instances[key]; !ok {
+		return errors.New("instance not registered")
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+)
+
+// RegisterPreShutdown 注册一个在服务关闭前执行的钩子，适合用来下线注册、刷缓存等
+// 需要在连接被关闭之前完成的动作。钩子会在 tryClose 中并发执行，每个钩子有自己的
+// goroutine，并受 MaxCloseWaitTime 限制。
+func (s *Server) RegisterPreShutdown(fn func(context.Context) error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.onPreShutdownHooks = append(s.onPreShutdownHooks, fn)
+}
+
+// RegisterOnShutdown 注册一个在所有 Service 关闭完成后执行的钩子，适合用来做打点
+// 上报、资源回收等收尾工作。钩子同样并发执行并受 MaxCloseWaitTime 限制。
+func (s *Server) RegisterOnShutdown(fn func(context.Context) error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.onShutdownHooks = append(s.onShutdownHooks, fn)
+}
+
+// Run 启动所有已添加的 Service，并阻塞直到收到 SIGINT/SIGTERM/SIGQUIT/SIGHUP
+// （Windows 下为其子集）、某个 Service 返回致命错误，或者传入的 ctx 被取消为止，
+// 随后执行优雅关闭流程。ctx 为 nil 时等价于 context.Background()。
+func (s *Server) Run(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if s.closeCh == nil {
+		s.closeCh = make(chan struct{})
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, shutdownSignals...)
+	defer signal.Stop(sigCh)
+
+	// 启动Serve前，先向名字服务注册实现了InstanceProvider的Service并开始心跳续约
+	s.registerAll(ctx)
+
+	// 每个Service独立一个goroutine跑Serve，errCh收集第一个致命错误
+	errCh := make(chan error, len(s.services))
+	for name, service := range s.services {
+		go func(name string, srv Service) {
+			if err := srv.Serve(); err != nil {
+				s.failedServices.Store(name, err)
+				errCh <- fmt.Errorf("service %s serve failed: %w", name, err)
+			}
+		}(name, service)
+	}
+
+	var runErr error
+	select {
+	case <-sigCh:
+		// 收到退出信号，进入优雅关闭流程
+	case runErr = <-errCh:
+		// 某个Service致命退出，其余Service仍然走一遍优雅关闭
+	case <-ctx.Done():
+		runErr = ctx.Err()
+	case <-s.closeCh:
+		// 调用方绕过Run直接调用了Close，Run需要跟着醒过来而不是永远阻塞
+	}
+
+	closeErr := s.Close(nil)
+	return errors.Join(runErr, closeErr)
+}
+
+// runHooks 并发执行hooks，每个hook拥有自己的goroutine，整体受waitTime限制，
+// 所有hook返回的error通过errors.Join聚合后返回。
+func runHooks(ctx context.Context, hooks []func(context.Context) error, waitTime time.Duration) error {
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	hctx, cancel := context.WithTimeout(ctx, waitTime)
+	defer cancel()
+
+	var mu sync.Mutex
+	var errs []error
+	var wg sync.WaitGroup
+	for _, h := range hooks {
+		wg.Add(1)
+		go func(fn func(context.Context) error) {
+			defer wg.Done()
+			if err := fn(hctx); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}(h)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		// 所有hook执行完成
+	case <-hctx.Done():
+		// 等待超时，未完成的hook不再等待
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return errors.Join(errs...)
+}
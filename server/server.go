@@ -7,16 +7,32 @@ import (
 	"errors"
 	"sync"
 	"time"
+
+	"github.com/Is-Raining/myTinyRpc/codec"
 )
 
 // Server 是一個tinyRpc的server，包括很多个service
 type Server struct {
-	MaxCloseWaitTime time.Duration      //服務最大等待時間
-	services         map[string]Service //服务的映射，k是服务名称，v是服务实体
-	mux              sync.Mutex         //互斥锁（Mutex）结构，避免多个协程同时修改或访问同一个资源导致的竞态条件
-	failedServices   sync.Map           // 失败的服务的map
-	closeCh          chan struct{}      //空结构体用于在通道上进行信号传递或同步操作
-	closeOnce        sync.Once          //用于一些只需要执行一次的操作，比如初始化一个全局变量、注册信号处理程序等。
+	MaxCloseWaitTime  time.Duration                 //服務最大等待時間
+	RejectAfter       time.Duration                 //对实现了Draining的Service调用Drain后，先等待这段时间再开始轮询InFlight是否归零，留给下游负载均衡感知下线的窗口期
+	HeartbeatInterval time.Duration                 //名字服务心跳续约周期，不设置时取Instance.TTL/3
+	services          map[string]Service            //服务的映射，k是服务名称，v是服务实体
+	inFlight          int64                         //所有Service正在处理中的请求数，由各Service自行通过AddInFlight/DoneInFlight维护
+	registry          Registry                      //名字服务注册中心，通过WithRegistry设置
+	registryCancels   map[string]func()             //每个服务注册后拿到的cancel，注销时调用
+	wrappers          []HandlerWrapper              //Use注册的中间件链，按注册顺序依次包裹
+	codecs            map[string]codec.NewCodecFunc //RegisterCodec登记的编码类型，按类型名覆盖codec包的默认实现
+	overload          *OverloadProtector            //WithOverloadProtector开启后的自适应过载保护器，为nil表示未开启
+	pool              *workerPool                   //WithWorkerPool开启后的有界协程池，为nil表示未开启，退化为裸起goroutine
+	mux               sync.Mutex                    //互斥锁（Mutex）结构，避免多个协程同时修改或访问同一个资源导致的竞态条件
+	failedServices    sync.Map                      // 失败的服务的map
+	closeCh           chan struct{}                 //Run()阻塞等待的信号通道，Close()会关闭它以便唤醒Run()
+	closeChOnce       sync.Once                     //保护closeCh只被关闭一次，避免Close()被并发/重复调用时panic
+	closeOnce         sync.Once                     //用于一些只需要执行一次的操作，比如初始化一个全局变量、注册信号处理程序等。
+	closeErr          error                         //tryClose的结果，所有等待在closeOnce上的调用方都会拿到同一个结果
+
+	onPreShutdownHooks []func(context.Context) error //关闭前执行的钩子，比如下线注册、刷缓存
+	onShutdownHooks    []func(context.Context) error //服务关闭完成后执行的钩子，比如打点上报、资源回收
 }
 
 // AddService 添加Service到map中
@@ -53,34 +69,28 @@ func (s *Server) Register(serviceDesc interface{}, serviceImpl interface{}) erro
 }
 
 func (s *Server) Close(ch chan struct{}) error {
-	if s.closeCh != nil {
-		close(s.closeCh)
-	}
+	s.closeChOnce.Do(func() {
+		if s.closeCh != nil {
+			close(s.closeCh)
+		}
+	})
 
-	s.tryClose()
+	err := s.tryClose()
 
 	if ch != nil {
 		ch <- struct{}{}
 	}
-	return nil
+	return err
 }
 
-func (s *Server) tryClose() {
+func (s *Server) tryClose() error {
 	// 定义只执行一次的闭包函数
 	// 函数字面量（Function Literal）或匿名函数
 	// 匿名函数常用于需要定义临时函数的场景，比如作为函数参数进行传递、在协程中进行并发执行等。
 	fn := func() {
-
-		// 在关闭服务之前执行关闭钩子函数(不实现)
-		//s.mux.Lock()
-		//for _, f := range s.onShutdownHooks {
-		//	f()
-		//}
-		//s.mux.Unlock()
-
 		// 关闭所有服务
 		closeWaitTime := s.MaxCloseWaitTime
-		if closeWaitTime < MaxCloseWaitTime {
+		if closeWaitTime <= 0 {
 			closeWaitTime = MaxCloseWaitTime
 		}
 
@@ -90,6 +100,24 @@ func (s *Server) tryClose() {
 		ctx, cancel := context.WithTimeout(context.Background(), closeWaitTime)
 		defer cancel()
 
+		var errs []error
+
+		// 先把未失败的服务从名字服务上注销，让负载均衡尽快停止路由流量
+		s.deregisterAll(ctx)
+
+		// 关闭服务之前执行 pre-shutdown 钩子，比如下线注册、刷缓存
+		s.mux.Lock()
+		preHooks := s.onPreShutdownHooks
+		postHooks := s.onShutdownHooks
+		s.mux.Unlock()
+
+		if err := runHooks(ctx, preHooks, closeWaitTime); err != nil {
+			errs = append(errs, err)
+		}
+
+		// 让所有实现了Draining的Service先进入排水模式：拒绝新请求，等待存量请求跑完
+		s.drain(ctx)
+
 		// 使用 WaitGroup 跟踪所有服务的关闭操作
 		var wg sync.WaitGroup
 		for name, service := range s.services {
@@ -99,31 +127,49 @@ func (s *Server) tryClose() {
 			}
 
 			wg.Add(1)
-			go func(srv Service) {
-				defer wg.Done()
-
-				// 创建一个用于通知服务关闭的通道
-				c := make(chan struct{}, 1)
-				// 对该Service执行关闭
-				go func() {
-					err := srv.Close(c)
-					if err != nil {
-						//关闭失败
+			closeTask := func(srv Service) func() {
+				return func() {
+					defer wg.Done()
+
+					// 创建一个用于通知服务关闭的通道
+					c := make(chan struct{}, 1)
+					// 只有这里对srv.Close的调用提交给协程池，外层这个等待任务
+					// 本身裸起goroutine，避免同一个池里外层等待任务占满worker、
+					// 内层Close任务永远排不上导致的自我嵌套死锁
+					_ = s.Go(ctx, func() {
+						err := srv.Close(c)
+						if err != nil {
+							//关闭失败
+						}
+					})
+
+					// 等待服务关闭或上下文超时
+					select {
+					case <-c:
+						// 服务成功关闭
+					case <-ctx.Done():
+						// 服务关闭超时
 					}
-				}()
-
-				// 等待服务关闭或上下文超时
-				select {
-				case <-c:
-					// 服务成功关闭
-				case <-ctx.Done():
-					// 服务关闭超时
 				}
 			}(service)
+			go closeTask()
 		}
 		wg.Wait()
+
+		// 所有关闭任务都提交完了，协程池后续也不会再有新任务，随server一起释放
+		if s.pool != nil {
+			s.pool.Close()
+		}
+
+		// 所有服务关闭之后执行 shutdown 钩子，比如打点上报、资源回收
+		if err := runHooks(ctx, postHooks, closeWaitTime); err != nil {
+			errs = append(errs, err)
+		}
+
+		s.closeErr = errors.Join(errs...)
 	}
 
-	// 只执行一次闭包函数
+	// 只执行一次闭包函数，后续调用都会拿到第一次执行的结果
 	s.closeOnce.Do(fn)
+	return s.closeErr
 }
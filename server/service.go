@@ -1,9 +1,16 @@
 package server
 
-import "time"
+import (
+	"errors"
+	"time"
+)
 
 const MaxCloseWaitTime = 10 * time.Second
 
+// ErrDraining 是Service处于排水模式时拒绝新请求返回的错误，调用方应将其视为
+// 可重试的错误（等价于HTTP的503），换一个实例重试即可。
+var ErrDraining = errors.New("service is draining, retry on another instance")
+
 // Service is the interface that provides services.
 type Service interface {
 	// Register 注册服务.
@@ -14,6 +21,15 @@ type Service interface {
 	Close(chan struct{}) error
 }
 
+// Draining 是一个可选接口，Service 实现后可以在关闭前先进入排水模式：
+// 拒绝新请求，已经在处理中的请求继续完成，待存量请求跑完后再真正关闭连接。
+// Server.tryClose 会优先对实现了该接口的Service调用Drain。
+type Draining interface {
+	// Drain 让Service进入排水模式，新到来的RPC应返回ErrDraining一类的可重试错误。
+	Drain() error
+}
+
 type ServiceDesc struct {
 	ServiceName string
+	CodecType   string //连接建立时协商出的编码类型，对应codec包里登记的MIME风格类型名，如application/gob
 }
@@ -0,0 +1,11 @@
+//go:build windows
+
+package server
+
+import (
+	"os"
+	"syscall"
+)
+
+// shutdownSignals 是Windows下触发优雅关闭的信号集合，SIGQUIT/SIGHUP在Windows上不存在。
+var shutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}